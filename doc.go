@@ -0,0 +1,22 @@
+// Package nafi (Not Another Form Interpreter) parses conf, dotenv, ini, json
+// and yaml configuration files behind a single dot notation lookup API.
+//
+// # Value resolution
+//
+// A key can be given a value by several layers besides the parsed file. Get,
+// Has, the typed getters, Unmarshal, AsFlatMap and ToDotenv all resolve a key
+// through the same function, taking the first layer that has a value:
+//
+//  1. flag: a flag bound with BindFlag, only once it has been set
+//  2. set: a value from Set
+//  3. env: an environment variable, when enabled with WithEnvPrefix
+//  4. file: the parsed config content
+//  5. computed: a function registered with SetComputed
+//  6. default: a value from SetDefault
+//
+// A layer holding an empty string still counts as set.
+package nafi
+
+// Precedence is the order in which layers are consulted when resolving a key,
+// highest first. See the package documentation for details.
+const Precedence = "flag > set > env > file > computed > default"
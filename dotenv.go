@@ -0,0 +1,210 @@
+package nafi
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// type for file writing function so it can be mocked
+type fileWriterFunc func(path string, data []byte) error
+
+var writeFile fileWriterFunc = func(path string, data []byte) error {
+	return os.WriteFile(path, data, 0o600)
+}
+
+// parseDotenv reads KEY=value lines, as used by docker-compose .env files.
+//
+// Lines may start with "export ". Double quoted values may span lines and
+// support \n, \r, \t, \" and \\ escapes, with "$$" read as "$"; single quoted
+// values are literal; unquoted values end at a " #" comment.
+func parseDotenv(content []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+	lineNo := 0
+	for len(text) > 0 {
+		var line string
+		line, text = cutLine(text)
+		lineNo++
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		val := strings.TrimLeft(parts[1], " \t")
+
+		switch {
+		case strings.HasPrefix(val, `"`):
+			// Closing quote may be on a later line
+			rest := val[1:]
+			start := lineNo
+			for {
+				unquoted, ok := unescapeDotenv(rest)
+				if ok {
+					val = unquoted
+					break
+				}
+				if text == "" {
+					return nil, fmt.Errorf("dotenv: unterminated quoted value for %s on line %d", key, start)
+				}
+				var next string
+				next, text = cutLine(text)
+				lineNo++
+				rest += "\n" + next
+			}
+		case strings.HasPrefix(val, "'"):
+			end := strings.Index(val[1:], "'")
+			if end < 0 {
+				return nil, fmt.Errorf("dotenv: unterminated quoted value for %s on line %d", key, lineNo)
+			}
+			val = val[1 : end+1]
+		default:
+			if idx := strings.Index(val, " #"); idx >= 0 {
+				val = val[:idx]
+			}
+			val = strings.TrimSpace(val)
+		}
+		values[key] = val
+	}
+	return values, nil
+}
+
+// split off the first line of text
+func cutLine(text string) (string, string) {
+	line, rest, _ := strings.Cut(text, "\n")
+	return line, rest
+}
+
+// unescapeDotenv decodes a double quoted value up to its closing quote,
+// reporting false if the closing quote is missing
+func unescapeDotenv(s string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return b.String(), true
+		case '\\':
+			if i+1 == len(s) {
+				b.WriteByte('\\')
+				continue
+			}
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\':
+				b.WriteByte(s[i])
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(s[i])
+			}
+		case '$':
+			// "$$" is a literal "$", as in docker-compose
+			if i+1 < len(s) && s[i+1] == '$' {
+				i++
+			}
+			b.WriteByte('$')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", false
+}
+
+// ToDotenv serializes every key as a sorted KEY=value line.
+//
+// Keys from other formats are flattened to their full path, with dots and
+// any other character not valid in an environment variable name replaced by
+// underscores, and upper cased, so "server.base-url" becomes SERVER_BASE_URL.
+// List elements are written by index (HOSTS_0, HOSTS_1) and nulls as empty
+// values. Values containing whitespace, quotes, backslashes or "#" are double
+// quoted and escaped. Values are resolved as Get resolves them, following
+// Precedence.
+func (c *configParserObj) ToDotenv() ([]byte, error) {
+	return c.dotenvContent(c.AsFlatMap())
+}
+
+// serialize flat keys and values as sorted KEY=value lines
+func (c *configParserObj) dotenvContent(flat map[string]string) ([]byte, error) {
+	sources := make(map[string]string, len(flat))
+	names := make([]string, 0, len(flat))
+	for key := range flat {
+		name := key
+		if c.fileType != "dotenv" {
+			name = dotenvKey(key)
+		}
+		if other, ok := sources[name]; ok {
+			return nil, fmt.Errorf("dotenv: keys %q and %q both map to %s", other, key, name)
+		}
+		sources[name] = key
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		val := flat[sources[name]]
+		if kind, _ := c.Kind(sources[name]); kind == KindNull {
+			val = ""
+		}
+		b.WriteString(name + "=" + quoteDotenvValue(val) + "\n")
+	}
+	return []byte(b.String()), nil
+}
+
+// Save writes the config back to a file. Only dotenv parsers can be saved.
+// Only values read from the file are written; flags, Set, the environment,
+// SetComputed and SetDefault are not persisted.
+//
+// Example - err := configParser.Save(".env")
+func (c *configParserObj) Save(path string) error {
+	if c.fileType != "dotenv" {
+		return errors.New("save is not supported for file type " + c.fileType)
+	}
+	content, err := c.dotenvContent(c.flatten())
+	if err != nil {
+		return err
+	}
+	return writeFile(path, content)
+}
+
+// convert a dot notation key to an environment variable name
+func dotenvKey(key string) string {
+	key = strings.ReplaceAll(key, `\.`, ".")
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+}
+
+// quote and escape a value if it would not survive unquoted. docker-compose
+// interpolates "$" in unquoted and double quoted values, so values containing
+// "$" are single quoted, or double quoted with "$" doubled when they also
+// contain a single quote or line break.
+func quoteDotenvValue(val string) string {
+	if !strings.ContainsAny(val, " \t\r\n#\"'\\$") {
+		return val
+	}
+	if strings.Contains(val, "$") && !strings.ContainsAny(val, "'\r\n") {
+		return "'" + val + "'"
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "$", "$$")
+	return `"` + replacer.Replace(val) + `"`
+}
@@ -0,0 +1,242 @@
+package nafi
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Compose-style application config exported to .env
+const composeYAML = `
+compose_project_name: shop
+postgres:
+  user: shop
+  password: "s3cr3t #1"
+  replica_password: 'pa$$word'
+  db: shop
+web:
+  port: 8080
+  memory_limit: 10485760
+  debug: false
+  allowed_hosts: "localhost example.com"
+  motd: "Welcome!\nSay \"hi\""
+  api-gateway:
+    upstream:
+      url: http://api:9000/v1
+`
+
+const composeEnv = `COMPOSE_PROJECT_NAME=shop
+POSTGRES_DB=shop
+POSTGRES_PASSWORD="s3cr3t #1"
+POSTGRES_REPLICA_PASSWORD='pa$$word'
+POSTGRES_USER=shop
+WEB_ALLOWED_HOSTS="localhost example.com"
+WEB_API_GATEWAY_UPSTREAM_URL=http://api:9000/v1
+WEB_DEBUG=false
+WEB_MEMORY_LIMIT=10485760
+WEB_MOTD="Welcome!\nSay \"hi\""
+WEB_PORT=8080
+`
+
+// The same config as json, where every number decodes as a float
+const composeJSON = `{
+  "compose_project_name": "shop",
+  "postgres": {
+    "user": "shop",
+    "password": "s3cr3t #1",
+    "replica_password": "pa$$word",
+    "db": "shop"
+  },
+  "web": {
+    "port": 8080,
+    "memory_limit": 10485760,
+    "debug": false,
+    "allowed_hosts": "localhost example.com",
+    "motd": "Welcome!\nSay \"hi\"",
+    "api-gateway": {"upstream": {"url": "http://api:9000/v1"}}
+  }
+}`
+
+// Test a compose-style config exports to the expected .env
+func TestToDotenvCompose(t *testing.T) {
+	sources := map[string]string{"yaml": composeYAML, "json": composeJSON}
+	for fileType, content := range sources {
+		t.Run(fileType, func(t *testing.T) {
+			parser, err := newConfigParserFromBytes(fileType, []byte(content))
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			out, err := parser.ToDotenv()
+			if err != nil {
+				t.Fatalf("ToDotenv unexpected error: %v", err)
+			}
+			if string(out) != composeEnv {
+				t.Errorf("ToDotenv() =\n%s\nwant\n%s", out, composeEnv)
+			}
+		})
+	}
+}
+
+// Test yaml survives a round trip through dotenv, modulo key naming
+func TestToDotenvRoundTrip(t *testing.T) {
+	source, err := newConfigParserFromBytes("yaml", []byte(composeYAML+`
+quotes: "it's a 'test'"
+tabs: "a\tb"
+price: "it's $5"
+backslash: 'C:\path\'
+empty: ""
+nothing: null
+hosts: [a, "b c"]
+upstreams:
+  - name: api
+    ports: [80, 443]
+`))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	out, err := source.ToDotenv()
+	if err != nil {
+		t.Fatalf("ToDotenv unexpected error: %v", err)
+	}
+	parsed, err := newConfigParserFromBytes("dotenv", out)
+	if err != nil {
+		t.Fatalf("dotenv parse error: %v\n%s", err, out)
+	}
+
+	for _, line := range []string{"NOTHING=\n", "HOSTS_0=a\n", "HOSTS_1=\"b c\"\n", "UPSTREAMS_0_NAME=api\n", "UPSTREAMS_0_PORTS_1=443\n", "PRICE=\"it's $$5\"\n"} {
+		if !strings.Contains(string(out), line) {
+			t.Errorf("ToDotenv() missing line %q:\n%s", line, out)
+		}
+	}
+
+	expected := make(map[string]string)
+	for key, val := range source.AsFlatMap() {
+		if kind, _ := source.Kind(key); kind == KindNull {
+			val = ""
+		}
+		expected[dotenvKey(key)] = val
+	}
+	actual := parsed.AsFlatMap()
+	if len(actual) != len(expected) {
+		t.Errorf("round trip has %d keys; want %d", len(actual), len(expected))
+	}
+	for key, val := range expected {
+		if actual[key] != val {
+			t.Errorf("round trip %s = %q; want %q", key, actual[key], val)
+		}
+	}
+}
+
+// Test dots inside key names become underscores like path separators
+func TestToDotenvDottedNames(t *testing.T) {
+	parser, _ := newConfigParserFromBytes("yaml", []byte("dotted.key: a\nweb:\n  api.v1: b\n"))
+	out, err := parser.ToDotenv()
+	if err != nil {
+		t.Fatalf("ToDotenv unexpected error: %v", err)
+	}
+	if expected := "DOTTED_KEY=a\nWEB_API_V1=b\n"; string(out) != expected {
+		t.Errorf("ToDotenv() = %q; want %q", out, expected)
+	}
+}
+
+// Test dotenv parsing syntax
+func TestParseDotenv(t *testing.T) {
+	parser, err := newConfigParserFromBytes("dotenv", []byte(`
+# comment
+PLAIN=value
+export EXPORTED = yes
+INLINE=value # trailing comment
+HASH=abc#def
+SINGLE='literal \n $HOME'
+DOUBLE="line1\nline2 \"quoted\""
+DOLLAR="pa$$word $HOME"
+MULTI="first
+second"
+EMPTY=
+`))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	cases := map[string]string{
+		"PLAIN":    "value",
+		"EXPORTED": "yes",
+		"INLINE":   "value",
+		"HASH":     "abc#def",
+		"SINGLE":   `literal \n $HOME`,
+		"DOUBLE":   "line1\nline2 \"quoted\"",
+		"DOLLAR":   "pa$word $HOME",
+		"MULTI":    "first\nsecond",
+		"EMPTY":    "",
+	}
+	for lookup, expected := range cases {
+		if val, _ := parser.Get(lookup); val != expected {
+			t.Errorf("Get(%q) = %q; want %q", lookup, val, expected)
+		}
+	}
+
+	if _, err := newConfigParserFromBytes("dotenv", []byte("KEY=\"open\n")); err == nil {
+		t.Errorf("Expected unterminated quote error")
+	}
+}
+
+// Test keys that flatten to the same name are reported
+func TestToDotenvCollision(t *testing.T) {
+	parser, _ := newConfigParserFromBytes("json", []byte(`{"a": {"b": "1"}, "a_b": "2"}`))
+	_, err := parser.ToDotenv()
+	if err == nil || !strings.Contains(err.Error(), "A_B") {
+		t.Errorf("Expected collision error for A_B, got %v", err)
+	}
+}
+
+// Test Save writes dotenv parsers and rejects other types
+func TestSave(t *testing.T) {
+	var written string
+	writeFile = func(_ string, data []byte) error {
+		written = string(data)
+		return nil
+	}
+
+	parser, _ := newConfigParserFromBytes("dotenv", []byte("b_key=2\nA_KEY=\"x y\"\n"))
+	if err := parser.Save(".env"); err != nil {
+		t.Fatalf("Save unexpected error: %v", err)
+	}
+	if expected := "A_KEY=\"x y\"\nb_key=2\n"; written != expected {
+		t.Errorf("Save wrote %q; want %q", written, expected)
+	}
+
+	yamlParser, _ := newConfigParserFromBytes("yaml", []byte("a: 1"))
+	if err := yamlParser.Save("config.yaml"); err == nil {
+		t.Errorf("Expected error saving yaml parser")
+	}
+
+	// Other layers change what Get and ToDotenv see but are not saved
+	lookupEnv = func(key string) (string, bool) {
+		if key == "APP_TOKEN" {
+			return "from-env", true
+		}
+		return "", false
+	}
+	defer func() { lookupEnv = os.LookupEnv }()
+	layered, _ := newConfigParserFromBytes("dotenv", []byte("TOKEN=file\n"), WithEnvPrefix("APP"))
+	layered.SetDefault("EXTRA", "dflt")
+	if val, _ := layered.Get("TOKEN"); val != "from-env" {
+		t.Errorf("Get(%q) = %q; want %q", "TOKEN", val, "from-env")
+	}
+	if out, _ := layered.ToDotenv(); string(out) != "EXTRA=dflt\nTOKEN=from-env\n" {
+		t.Errorf("ToDotenv() = %q; want layered values", out)
+	}
+	if err := layered.Save(".env"); err != nil {
+		t.Fatalf("Save unexpected error: %v", err)
+	}
+	if expected := "TOKEN=file\n"; written != expected {
+		t.Errorf("Save wrote %q; want %q", written, expected)
+	}
+
+	writeFile = func(_ string, _ []byte) error {
+		return errors.New("mock write error")
+	}
+	if err := parser.Save(".env"); err == nil || err.Error() != "mock write error" {
+		t.Errorf("Expected 'mock write error', got %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package nafi
+
+import (
+	"sync"
+	"testing"
+)
+
+// Test lookups continue into documents embedded in string values
+func TestEmbeddedDocuments(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileType string
+		content  string
+		cases    map[string]string
+	}{
+		{
+			name:     "json in yaml",
+			fileType: "yaml",
+			content: `
+policy: '{"allow": ["a", "b"], "owner": {"name": "ops"}}'
+plain: value
+`,
+			cases: map[string]string{
+				"policy.allow":      "[a b]",
+				"policy.owner.name": "ops",
+				"policy.missing":    "",
+				"plain":             "value",
+				"plain.child":       "",
+			},
+		},
+		{
+			name:     "yaml in json",
+			fileType: "json",
+			content: `
+{
+  "service": {
+    "settings": "db:\n  host: localhost\n  port: 5432\n"
+  }
+}
+`,
+			cases: map[string]string{
+				"service.settings.db.host": "localhost",
+				"service.settings.db.port": "5432",
+				"service.settings.db.user": "",
+			},
+		},
+		{
+			name:     "yaml flow in yaml",
+			fileType: "yaml",
+			content: `
+policy: '{allow: [a, b], owner: {name: ops}}'
+hosts: '[web, db]'
+`,
+			cases: map[string]string{
+				"policy.allow.1":    "b",
+				"policy.owner.name": "ops",
+				"hosts.0":           "web",
+			},
+		},
+		{
+			name:     "looks like json",
+			fileType: "yaml",
+			content: `
+note: '{not: valid json'
+list: '[1, 2'
+`,
+			cases: map[string]string{
+				"note":     "{not: valid json",
+				"note.not": "",
+				"list":     "[1, 2",
+				"list.0":   "",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := newConfigParserFromBytes(tt.fileType, []byte(tt.content), WithEmbeddedDocuments())
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			for lookup, expected := range tt.cases {
+				val, err := parser.Get(lookup)
+				if err != nil {
+					t.Errorf("Get(%q) unexpected error: %v", lookup, err)
+				}
+				if val != expected {
+					t.Errorf("Get(%q) = %q; want %q", lookup, val, expected)
+				}
+			}
+		})
+	}
+}
+
+// Test embedded documents are parsed once and left alone when the option is off
+func TestEmbeddedDocumentsCacheAndOptIn(t *testing.T) {
+	content := []byte(`policy: '{"allow": "a"}'`)
+
+	t.Run("disabled by default", func(t *testing.T) {
+		parser, _ := newConfigParserFromBytes("yaml", content)
+		if val, _ := parser.Get("policy.allow"); val != "" {
+			t.Errorf("Get(%q) = %q; want empty string", "policy.allow", val)
+		}
+	})
+
+	t.Run("cached per leaf", func(t *testing.T) {
+		parser, _ := newConfigParserFromBytes("yaml", content, WithEmbeddedDocuments())
+		if val, _ := parser.Get("policy.allow"); val != "a" {
+			t.Fatalf("Get(%q) = %q; want %q", "policy.allow", val, "a")
+		}
+		// Replace the cached document to prove the string is not parsed again
+		parser.embeddedCache.Store("policy", map[string]interface{}{"allow": "cached"})
+		if val, _ := parser.Get("policy.allow"); val != "cached" {
+			t.Errorf("Get(%q) = %q; want cached value", "policy.allow", val)
+		}
+	})
+}
+
+// Test concurrent lookups share the embedded document cache safely; run with -race
+func TestEmbeddedDocumentsConcurrent(t *testing.T) {
+	parser, err := newConfigParserFromBytes("yaml", []byte(`policy: '{"a": "1", "b": {"c": "2"}}'`), WithEmbeddedDocuments())
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if val, _ := parser.Get("policy.a"); val != "1" {
+					t.Errorf("Get(%q) = %q; want %q", "policy.a", val, "1")
+					return
+				}
+				if val, _ := parser.Get("policy.b.c"); val != "2" {
+					t.Errorf("Get(%q) = %q; want %q", "policy.b.c", val, "2")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
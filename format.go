@@ -0,0 +1,56 @@
+package nafi
+
+import (
+	"errors"
+	"sync"
+)
+
+// Format decodes raw content of a custom file type into nested maps.
+//
+// Parsed data is looked up exactly like json and yaml, so nested values use
+// dot notation. Parse should return an empty map, not an error, for empty
+// content.
+type Format interface {
+	Parse(content []byte) (map[string]interface{}, error)
+}
+
+// FormatFunc adapts an ordinary function to the Format interface.
+type FormatFunc func(content []byte) (map[string]interface{}, error)
+
+// Parse calls f(content).
+func (f FormatFunc) Parse(content []byte) (map[string]interface{}, error) {
+	return f(content)
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   = make(map[string]Format)
+)
+
+// RegisterFormat makes a custom file type available to ConfigParser under
+// name. Registering a name again replaces the previous format; built-in
+// types cannot be replaced.
+//
+// The formattest package checks a format against the behaviour of the
+// built-in types.
+func RegisterFormat(name string, format Format) error {
+	if name == "" || format == nil {
+		return errors.New("format name and implementation are required")
+	}
+	switch name {
+	case "conf", "dotenv", "ini", "json", "yaml":
+		return errors.New("cannot replace built-in file type " + name)
+	}
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	formats[name] = format
+	return nil
+}
+
+// retrieve a registered format
+func lookupFormat(name string) (Format, bool) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	format, ok := formats[name]
+	return format, ok
+}
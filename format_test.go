@@ -0,0 +1,186 @@
+package nafi_test
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	nafi "github.com/Snowzei/NAFI"
+	"github.com/Snowzei/NAFI/formattest"
+)
+
+// Custom format reading "a.b = value" lines into nested maps
+var propertiesFormat = nafi.FormatFunc(func(content []byte) (map[string]interface{}, error) {
+	data := make(map[string]interface{})
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.New("missing '=' in line " + line)
+		}
+		path := strings.Split(strings.TrimSpace(parts[0]), ".")
+		current := data
+		for _, part := range path[:len(path)-1] {
+			next, ok := current[part].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				current[part] = next
+			}
+			current = next
+		}
+		current[path[len(path)-1]] = strings.TrimSpace(parts[1])
+	}
+	return data, scanner.Err()
+})
+
+// Test every built-in format passes the conformance suite
+func TestBuiltinFormatConformance(t *testing.T) {
+	tests := []struct {
+		fileType     string
+		sample       string
+		expectations map[string]string
+	}{
+		{
+			fileType: "conf",
+			sample: `
+# comment
+key1=value1
+key2 = value with spaces
+empty =
+dotted.key = nested
+`,
+			expectations: map[string]string{
+				"key1":       "value1",
+				"key2":       "value with spaces",
+				"empty":      "",
+				"dotted.key": "nested",
+			},
+		},
+		{
+			fileType: "dotenv",
+			sample: `
+PLAIN=value
+export EXPORTED=yes
+QUOTED="two words # not a comment"
+EMPTY=
+`,
+			expectations: map[string]string{
+				"PLAIN":    "value",
+				"EXPORTED": "yes",
+				"QUOTED":   "two words # not a comment",
+				"EMPTY":    "",
+			},
+		},
+		{
+			fileType: "ini",
+			sample: `
+top = level
+dotted.key = default
+
+[section1]
+foo = bar
+empty =
+
+[section2]
+qux = quux
+tls.cert = server.pem
+
+[php]
+arr[0] = x
+`,
+			expectations: map[string]string{
+				"top":                "level",
+				`dotted\.key`:        "default",
+				"section1.foo":       "bar",
+				"section1.empty":     "",
+				"section2.qux":       "quux",
+				`section2.tls\.cert`: "server.pem",
+				"php.arr[0]":         "x",
+			},
+		},
+		{
+			fileType: "json",
+			sample: `
+{
+  "plain": "top",
+  "number": 22,
+  "large": 10485760,
+  "ratio": 2000000.5,
+  "empty": "",
+  "section1": {"foo": "bar", "deeper": {"baz": true}}
+}
+`,
+			expectations: map[string]string{
+				"plain":               "top",
+				"number":              "22",
+				"large":               "10485760",
+				"ratio":               "2000000.5",
+				"empty":               "",
+				"section1.foo":        "bar",
+				"section1.deeper.baz": "true",
+			},
+		},
+		{
+			fileType: "yaml",
+			sample: `
+plain: top
+empty: ""
+dotted.key: 5
+section1:
+  dotted.inner: 6
+  foo: bar
+  deeper:
+    baz: 3
+`,
+			expectations: map[string]string{
+				"plain":                  "top",
+				"empty":                  "",
+				`dotted\.key`:            "5",
+				`section1.dotted\.inner`: "6",
+				"section1.foo":           "bar",
+				"section1.deeper.baz":    "3",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileType, func(t *testing.T) {
+			formattest.Run(t, tt.fileType, []byte(tt.sample), tt.expectations)
+		})
+	}
+}
+
+// Test a registered format is parsed and passes the conformance suite
+func TestRegisterFormat(t *testing.T) {
+	if err := nafi.RegisterFormat("properties", propertiesFormat); err != nil {
+		t.Fatalf("RegisterFormat unexpected error: %v", err)
+	}
+	formattest.Run(t, "properties", []byte(`
+name = demo
+server.host = localhost
+server.tls.enabled = true
+`), map[string]string{
+		"name":               "demo",
+		"server.host":        "localhost",
+		"server.tls.enabled": "true",
+	})
+}
+
+// Test registration errors
+func TestRegisterFormatErrors(t *testing.T) {
+	if err := nafi.RegisterFormat("yaml", propertiesFormat); err == nil {
+		t.Errorf("Expected error replacing built-in format")
+	}
+	if err := nafi.RegisterFormat("", propertiesFormat); err == nil {
+		t.Errorf("Expected error for empty format name")
+	}
+	if err := nafi.RegisterFormat("nilformat", nil); err == nil {
+		t.Errorf("Expected error for nil format")
+	}
+}
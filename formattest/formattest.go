@@ -0,0 +1,213 @@
+// Package formattest checks that a NAFI file type behaves like the built-in
+// formats through the public API.
+//
+// Authors of custom formats register them with nafi.RegisterFormat and call
+// Run from a test:
+//
+//	func TestMyFormat(t *testing.T) {
+//		nafi.RegisterFormat("myformat", myFormat{})
+//		formattest.Run(t, "myformat", sample, map[string]string{
+//			"server.host": "localhost",
+//		})
+//	}
+package formattest
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	nafi "github.com/Snowzei/NAFI"
+)
+
+// key that no sample is expected to contain
+const missingKey = "nafi_formattest_missing"
+
+// Run parses sample as file type name and checks Get, Has, Keys, AsFlatMap,
+// Sub and Unmarshal against expectations, a map of keys in dot notation to the
+// value Get must return. Every expected key must be present in sample, though
+// its value may be empty.
+//
+// Run also checks invariants every format must hold: missing keys return an
+// empty string without error, empty content parses to an empty config, and
+// malformed paths such as "a..b" or "a." never match.
+func Run(t *testing.T, name string, sample []byte, expectations map[string]string) {
+	t.Helper()
+
+	parser, err := nafi.ConfigParserFromBytes(sample, name)
+	if err != nil {
+		t.Fatalf("ConfigParserFromBytes(%q) unexpected error: %v", name, err)
+	}
+	keys := make([]string, 0, len(expectations))
+	for key := range expectations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	t.Run("Get", func(t *testing.T) {
+		for _, key := range keys {
+			val, err := parser.Get(key)
+			if err != nil {
+				t.Errorf("Get(%q) unexpected error: %v", key, err)
+			}
+			if val != expectations[key] {
+				t.Errorf("Get(%q) = %q; want %q", key, val, expectations[key])
+			}
+		}
+	})
+
+	t.Run("Has", func(t *testing.T) {
+		for _, key := range keys {
+			if !parser.Has(key) {
+				t.Errorf("Has(%q) = false; want true", key)
+			}
+		}
+	})
+
+	t.Run("Keys", func(t *testing.T) {
+		listed := parser.Keys()
+		if !sort.StringsAreSorted(listed) {
+			t.Errorf("Keys() = %v; want sorted", listed)
+		}
+		seen := make(map[string]bool, len(listed))
+		for _, key := range listed {
+			if seen[key] {
+				t.Errorf("Keys() lists %q more than once", key)
+			}
+			seen[key] = true
+			if !parser.Has(key) {
+				t.Errorf("Keys() lists %q but Has(%q) = false", key, key)
+			}
+		}
+		for _, key := range keys {
+			if !seen[key] {
+				t.Errorf("Keys() = %v; missing %q", listed, key)
+			}
+		}
+	})
+
+	t.Run("AsFlatMap", func(t *testing.T) {
+		flat := parser.AsFlatMap()
+		if len(flat) != len(parser.Keys()) {
+			t.Errorf("AsFlatMap() has %d entries; Keys() has %d", len(flat), len(parser.Keys()))
+		}
+		for key, val := range flat {
+			if got, _ := parser.Get(key); got != val {
+				t.Errorf("AsFlatMap()[%q] = %q; Get(%q) = %q", key, val, key, got)
+			}
+		}
+		for _, key := range keys {
+			if val, ok := flat[key]; !ok || val != expectations[key] {
+				t.Errorf("AsFlatMap()[%q] = %q, %v; want %q", key, val, ok, expectations[key])
+			}
+		}
+	})
+
+	t.Run("Sub", func(t *testing.T) {
+		for _, key := range keys {
+			parts := splitFirst(key)
+			if len(parts) != 2 {
+				continue
+			}
+			sub := parser.Sub(parts[0])
+			if sub == nil {
+				t.Errorf("Sub(%q) = nil; want parser containing %q", parts[0], parts[1])
+				continue
+			}
+			if val, err := sub.Get(parts[1]); err != nil || val != expectations[key] {
+				t.Errorf("Sub(%q).Get(%q) = %q, %v; want %q", parts[0], parts[1], val, err, expectations[key])
+			}
+		}
+		if sub := parser.Sub(missingKey); sub != nil {
+			t.Errorf("Sub(%q) = %v; want nil", missingKey, sub)
+		}
+	})
+
+	t.Run("Unmarshal", func(t *testing.T) {
+		// One string field per expectation, tagged with its full key
+		fields := make([]reflect.StructField, len(keys))
+		for i, key := range keys {
+			fields[i] = reflect.StructField{
+				Name: "F" + strconv.Itoa(i),
+				Type: reflect.TypeOf(""),
+				Tag:  reflect.StructTag("nafi:" + strconv.Quote(key)),
+			}
+		}
+		target := reflect.New(reflect.StructOf(fields))
+		if err := parser.Unmarshal(target.Interface()); err != nil {
+			t.Fatalf("Unmarshal unexpected error: %v", err)
+		}
+		for i, key := range keys {
+			if val := target.Elem().Field(i).String(); val != expectations[key] {
+				t.Errorf("Unmarshal field for %q = %q; want %q", key, val, expectations[key])
+			}
+		}
+	})
+
+	t.Run("missing keys", func(t *testing.T) {
+		lookups := []string{missingKey, missingKey + "." + missingKey}
+		for _, key := range keys {
+			lookups = append(lookups, key+"."+missingKey)
+		}
+		for _, key := range lookups {
+			val, err := parser.Get(key)
+			if err != nil {
+				t.Errorf("Get(%q) unexpected error: %v", key, err)
+			}
+			if val != "" {
+				t.Errorf("Get(%q) = %q; want empty string", key, val)
+			}
+			if parser.Has(key) {
+				t.Errorf("Has(%q) = true; want false", key)
+			}
+		}
+	})
+
+	t.Run("delimiters", func(t *testing.T) {
+		for _, key := range keys {
+			malformed := []string{key + ".", "." + key}
+			if strings.Contains(key, ".") {
+				malformed = append(malformed, strings.Replace(key, ".", "..", 1))
+			}
+			for _, bad := range malformed {
+				if parser.Has(bad) {
+					t.Errorf("Has(%q) = true; want false", bad)
+				}
+			}
+		}
+	})
+
+	t.Run("empty content", func(t *testing.T) {
+		for _, content := range []string{"", "\n  \n"} {
+			empty, err := nafi.ConfigParserFromBytes([]byte(content), name)
+			if err != nil {
+				t.Errorf("ConfigParserFromBytes(%q) unexpected error: %v", content, err)
+				continue
+			}
+			if listed := empty.Keys(); len(listed) != 0 {
+				t.Errorf("Keys() on %q = %v; want none", content, listed)
+			}
+			if flat := empty.AsFlatMap(); len(flat) != 0 {
+				t.Errorf("AsFlatMap() on %q = %v; want empty", content, flat)
+			}
+			if val, err := empty.Get(missingKey); err != nil || val != "" {
+				t.Errorf("Get(%q) on %q = %q, %v; want empty string", missingKey, content, val, err)
+			}
+		}
+	})
+}
+
+// split a key at its first dot that is not escaped as "\."
+func splitFirst(key string) []string {
+	for i := 0; i < len(key); i++ {
+		switch {
+		case key[i] == '\\' && i+1 < len(key) && key[i+1] == '.':
+			i++
+		case key[i] == '.':
+			return []string{key[:i], key[i+1:]}
+		}
+	}
+	return []string{key}
+}
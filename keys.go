@@ -0,0 +1,146 @@
+package nafi
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/ini.v1"
+)
+
+// Keys returns every leaf key in dot notation, sorted. List elements are
+// listed by index, e.g. "servers.0.host".
+//
+// Example - keys := configParser.Keys() // ["section.foo", "section.bar", ...]
+func (c *configParserObj) Keys() []string {
+	flat := c.AsFlatMap()
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// AsFlatMap returns every leaf key in dot notation mapped to its value, as Get would return it.
+// Keys given a value by flags, Set, SetComputed or SetDefault are included;
+// environment variables only override keys that are otherwise present.
+func (c *configParserObj) AsFlatMap() map[string]string {
+	flat := c.flatten()
+	for _, key := range c.layerKeys() {
+		flat[key] = ""
+	}
+	for key := range flat {
+		if val, ok := c.resolve(key); ok {
+			flat[key] = val
+		}
+	}
+	return flat
+}
+
+// Sub returns a parser scoped to a section or nested map, with keys relative to it.
+// Values from other layers below key are carried over. It returns nil if no
+// layer has a value below key.
+//
+// Example - sub := configParser.Sub("server"); host, err := sub.Get("host")
+func (c *configParserObj) Sub(key string) *configParserObj {
+	if key == "" || !c.supported() {
+		return nil
+	}
+	sub := &configParserObj{
+		data:            make(map[string]interface{}),
+		raw:             make(map[string]string),
+		fileType:        c.fileType,
+		fieldNameMapper: c.fieldNameMapper,
+		logger:          c.logger,
+		embedded:        c.embedded,
+		embeddedCache:   &sync.Map{},
+	}
+
+	found := false
+	switch c.fileType {
+	case "conf", "dotenv":
+		prefix := key + "."
+		for k, v := range c.raw {
+			if strings.HasPrefix(k, prefix) {
+				sub.raw[strings.TrimPrefix(k, prefix)] = v
+			}
+		}
+		found = len(sub.raw) > 0
+	case "ini":
+		// Section keys become keys of the default section
+		sub.iniFile = ini.Empty()
+		if sec, err := c.iniFile.GetSection(unescapeDots(key)); err == nil {
+			found = true
+			for _, k := range sec.Keys() {
+				if _, err := sub.iniFile.Section("").NewKey(k.Name(), k.Value()); err != nil {
+					return nil
+				}
+			}
+		}
+	default:
+		val, _ := c.nestedValue(key)
+		if data, ok := val.(map[string]interface{}); ok {
+			found = true
+			sub.data = data
+		}
+	}
+	c.scopeLayers(sub, key)
+	if !found && len(sub.layerKeys()) == 0 && !sub.hasEnv() {
+		return nil
+	}
+	return sub
+}
+
+// flatten collects every leaf value keyed by its dot notation path
+func (c *configParserObj) flatten() map[string]string {
+	flat := make(map[string]string)
+	switch c.fileType {
+	case "conf", "dotenv":
+		for k, v := range c.raw {
+			flat[k] = v
+		}
+	case "ini":
+		for _, sec := range c.iniFile.Sections() {
+			prefix := ""
+			if sec.Name() != ini.DefaultSection {
+				prefix = escapeDots(sec.Name()) + "."
+			}
+			for _, k := range sec.Keys() {
+				flat[prefix+escapeDots(k.Name())] = k.String()
+			}
+		}
+	default:
+		if c.nested() {
+			flattenNested(c.data, "", flat)
+		}
+	}
+	return flat
+}
+
+// walk nested maps, recording scalar values as leaves. Dots inside names are
+// escaped and list elements are keyed by index, so every key resolves with Get.
+func flattenNested(data map[string]interface{}, prefix string, flat map[string]string) {
+	for k, v := range data {
+		key := escapeDots(k)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		flattenValue(v, key, flat)
+	}
+}
+
+// record a value under key, descending into maps and lists
+func flattenValue(v interface{}, key string, flat map[string]string) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		flattenNested(val, key, flat)
+	case []interface{}:
+		for i, item := range val {
+			flattenValue(item, key+"."+strconv.Itoa(i), flat)
+		}
+	default:
+		flat[key] = formatValue(val)
+	}
+}
@@ -0,0 +1,95 @@
+package nafi
+
+import "strings"
+
+// ValueKind describes the shape of the value stored at a key.
+type ValueKind int
+
+const (
+	// KindScalar is a json/yaml number, boolean or string
+	KindScalar ValueKind = iota + 1
+	// KindMap is a json/yaml object or an ini section
+	KindMap
+	// KindList is a json/yaml array
+	KindList
+	// KindNull is an explicit json/yaml null
+	KindNull
+	// KindString is a conf, dotenv or ini value, which are always untyped strings
+	KindString
+)
+
+// String returns the lower case name of the kind
+func (k ValueKind) String() string {
+	switch k {
+	case KindScalar:
+		return "scalar"
+	case KindMap:
+		return "map"
+	case KindList:
+		return "list"
+	case KindNull:
+		return "null"
+	case KindString:
+		return "string"
+	default:
+		return "unknown"
+	}
+}
+
+// Kind reports the shape of the value at a key, and false if the key is missing.
+// Keys use the same path syntax as Get, including list indexes and escaped dots
+// for json/yaml data. Values from flags, Set, the environment, SetComputed or
+// SetDefault are untyped, so a key resolved from one of them is KindString.
+//
+// Example - kind, ok := configParser.Kind("servers[0].ports") // KindList, true
+func (c *configParserObj) Kind(key string) (ValueKind, bool) {
+	if _, ok := c.resolveAboveFile(key); ok {
+		return KindString, true
+	}
+	if kind, ok := c.fileKind(key); ok {
+		return kind, true
+	}
+	if _, ok := c.resolveBelowFile(key); ok {
+		return KindString, true
+	}
+	return 0, false
+}
+
+// fileKind reports the shape of a value in the parsed file
+func (c *configParserObj) fileKind(key string) (ValueKind, bool) {
+	switch c.fileType {
+	case "conf", "dotenv":
+		if _, ok := c.raw[key]; ok {
+			return KindString, true
+		}
+		return 0, false
+	case "ini":
+		if _, ok := c.lookup(key); ok {
+			return KindString, true
+		}
+		// A key naming a section is a map of its keys
+		if key != "" && !strings.Contains(key, ".") {
+			if _, err := c.iniFile.GetSection(key); err == nil {
+				return KindMap, true
+			}
+		}
+		return 0, false
+	}
+	if !c.nested() {
+		return 0, false
+	}
+	val, found := c.nestedValue(key)
+	if !found {
+		return 0, false
+	}
+	switch val.(type) {
+	case nil:
+		return KindNull, true
+	case map[string]interface{}:
+		return KindMap, true
+	case []interface{}:
+		return KindList, true
+	default:
+		return KindScalar, true
+	}
+}
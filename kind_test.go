@@ -0,0 +1,167 @@
+package nafi
+
+import "testing"
+
+// Test Kind reports the shape of values across a mixed fixture
+func TestKind(t *testing.T) {
+	type kindCase struct {
+		kind  ValueKind
+		found bool
+	}
+	tests := []struct {
+		name     string
+		fileType string
+		content  string
+		cases    map[string]kindCase
+	}{
+		{
+			name:     "yaml mixed",
+			fileType: "yaml",
+			content: `
+server:
+  host: localhost
+  ports: [80, 443]
+  tags:
+    - name: primary
+      weight: 1
+nothing: null
+dotted.key: 5
+`,
+			cases: map[string]kindCase{
+				"server":              {KindMap, true},
+				"server.host":         {KindScalar, true},
+				"server.ports":        {KindList, true},
+				"server.ports[1]":     {KindScalar, true},
+				"server.ports.0":      {KindScalar, true},
+				"server.tags[0]":      {KindMap, true},
+				"server.tags[0].name": {KindScalar, true},
+				"nothing":             {KindNull, true},
+				`dotted\.key`:         {KindScalar, true},
+				"dotted.key":          {0, false},
+				"server.ports[2]":     {0, false},
+				"missing":             {0, false},
+			},
+		},
+		{
+			name:     "json mixed",
+			fileType: "json",
+			content:  `{"list": [{"a": null}, "b"], "map": {"n": 1.5}}`,
+			cases: map[string]kindCase{
+				"list":      {KindList, true},
+				"list[0]":   {KindMap, true},
+				"list[0].a": {KindNull, true},
+				"list[1]":   {KindScalar, true},
+				"map":       {KindMap, true},
+				"map.n":     {KindScalar, true},
+				"list[x]":   {0, false},
+			},
+		},
+		{
+			name:     "ini sections",
+			fileType: "ini",
+			content:  "top = 1\n\n[server]\nhost = localhost\n",
+			cases: map[string]kindCase{
+				"top":         {KindString, true},
+				"server":      {KindMap, true},
+				"server.host": {KindString, true},
+				"server.port": {0, false},
+				"missing":     {0, false},
+			},
+		},
+		{
+			name:     "conf",
+			fileType: "conf",
+			content:  "key = value\n",
+			cases: map[string]kindCase{
+				"key":     {KindString, true},
+				"missing": {0, false},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := newConfigParserFromBytes(tt.fileType, []byte(tt.content))
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			for lookup, expected := range tt.cases {
+				kind, found := parser.Kind(lookup)
+				if kind != expected.kind || found != expected.found {
+					t.Errorf("Kind(%q) = %v, %v; want %v, %v", lookup, kind, found, expected.kind, expected.found)
+				}
+			}
+		})
+	}
+}
+
+// Test Get resolves list indexes and escaped dots
+func TestGetPathSyntax(t *testing.T) {
+	parser, err := newConfigParserFromBytes("yaml", []byte(`
+servers:
+  - host: a
+  - host: b
+dotted.key: value
+`))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	cases := map[string]string{
+		"servers[1].host": "b",
+		"servers.0.host":  "a",
+		`dotted\.key`:     "value",
+		"servers[2].host": "",
+		"dotted.key":      "",
+	}
+	for lookup, expected := range cases {
+		if val, _ := parser.Get(lookup); val != expected {
+			t.Errorf("Get(%q) = %q; want %q", lookup, val, expected)
+		}
+	}
+}
+
+// Test key splitting handles escapes and index shorthand
+func TestSplitKey(t *testing.T) {
+	cases := map[string][]string{
+		"a":            {"a"},
+		"a.b":          {"a", "b"},
+		`a\.b.c`:       {"a.b", "c"},
+		"list[0].name": {"list", "0", "name"},
+		"list[0][1]":   {"list", "0", "1"},
+		`back\slash`:   {`back\slash`},
+		"a..b":         {"a", "", "b"},
+	}
+	for key, expected := range cases {
+		parts := splitKey(key)
+		if len(parts) != len(expected) {
+			t.Errorf("splitKey(%q) = %q; want %q", key, parts, expected)
+			continue
+		}
+		for i := range parts {
+			if parts[i] != expected[i] {
+				t.Errorf("splitKey(%q) = %q; want %q", key, parts, expected)
+				break
+			}
+		}
+	}
+}
+
+// Test ini key names keep brackets instead of being read as list indexes
+func TestIniBracketKeys(t *testing.T) {
+	parser, err := newConfigParserFromBytes("ini", []byte("[php]\narr[0] = x\n"))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if val, _ := parser.Get("php.arr[0]"); val != "x" {
+		t.Errorf("Get(%q) = %q; want %q", "php.arr[0]", val, "x")
+	}
+	if !parser.Has("php.arr[0]") {
+		t.Errorf("Has(%q) = false; want true", "php.arr[0]")
+	}
+	if kind, ok := parser.Kind("php.arr[0]"); kind != KindString || !ok {
+		t.Errorf("Kind(%q) = %v, %v; want %v, true", "php.arr[0]", kind, ok, KindString)
+	}
+	if val, _ := parser.Get("php.arr.0"); val != "" {
+		t.Errorf("Get(%q) = %q; want empty string", "php.arr.0", val)
+	}
+}
@@ -0,0 +1,258 @@
+package nafi
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// type for environment lookup function so it can be mocked
+type envLookupFunc func(key string) (string, bool)
+
+var lookupEnv envLookupFunc = os.LookupEnv
+
+// environment listing function so it can be mocked
+var environ = os.Environ
+
+// Flag bound to a key
+type flagBinding struct {
+	fs   *flag.FlagSet
+	name string
+}
+
+// value returns the flag's value if it was set on the command line
+func (b flagBinding) value() (string, bool) {
+	set := false
+	b.fs.Visit(func(f *flag.Flag) {
+		if f.Name == b.name {
+			set = true
+		}
+	})
+	if !set {
+		return "", false
+	}
+	return b.fs.Lookup(b.name).Value.String(), true
+}
+
+// BindFlag makes a flag override a key once the flag has been set. A nil
+// FlagSet binds from flag.CommandLine.
+//
+// Example - err := configParser.BindFlag("server.port", nil, "port")
+func (c *configParserObj) BindFlag(key string, fs *flag.FlagSet, name string) error {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+	if fs.Lookup(name) == nil {
+		return errors.New("flag -" + name + " is not defined")
+	}
+	c.flags[c.layerKey(key)] = flagBinding{fs: fs, name: name}
+	return nil
+}
+
+// Set overrides the value for a key, taking precedence over the environment and file
+//
+// Example - configParser.Set("server.port", 8080)
+func (c *configParserObj) Set(key string, value interface{}) {
+	c.overrides[c.layerKey(key)] = fmt.Sprintf("%v", value)
+}
+
+// SetComputed registers a function supplying a key's value when the file does not.
+// The function is called on every lookup and reports false if it has no value.
+func (c *configParserObj) SetComputed(key string, fn func() (string, bool)) {
+	c.computed[c.layerKey(key)] = fn
+}
+
+// SetDefault sets the value used for a key when no other layer has one
+//
+// Example - configParser.SetDefault("server.port", 8080)
+func (c *configParserObj) SetDefault(key string, value interface{}) {
+	c.defaults[c.layerKey(key)] = fmt.Sprintf("%v", value)
+}
+
+// GetInt returns the value for a key as an int, or 0 if the key is missing
+func (c *configParserObj) GetInt(key string) (int, error) {
+	var n int
+	err := c.getAs(key, &n)
+	return n, err
+}
+
+// GetBool returns the value for a key as a bool, or false if the key is missing
+func (c *configParserObj) GetBool(key string) (bool, error) {
+	var b bool
+	err := c.getAs(key, &b)
+	return b, err
+}
+
+// GetFloat64 returns the value for a key as a float64, or 0 if the key is missing
+func (c *configParserObj) GetFloat64(key string) (float64, error) {
+	var f float64
+	err := c.getAs(key, &f)
+	return f, err
+}
+
+// GetDuration returns the value for a key as a time.Duration, or 0 if the key is missing
+func (c *configParserObj) GetDuration(key string) (time.Duration, error) {
+	var d time.Duration
+	err := c.getAs(key, &d)
+	return d, err
+}
+
+// resolve a key and convert it the same way Unmarshal converts fields
+func (c *configParserObj) getAs(key string, target interface{}) error {
+	if !c.supported() {
+		return errors.New("unsupported file type " + c.fileType)
+	}
+	val, found := c.resolve(key)
+	if !found {
+		return nil
+	}
+	fv := reflect.ValueOf(target).Elem()
+	if err := setFieldFromString(fv, val); err != nil {
+		return fmt.Errorf("cannot convert key %q to %s: %w", key, fv.Type(), err)
+	}
+	return nil
+}
+
+// resolve returns the effective value for a key, consulting each layer in Precedence order
+func (c *configParserObj) resolve(key string) (string, bool) {
+	if val, ok := c.resolveAboveFile(key); ok {
+		return val, true
+	}
+	if val, ok := c.lookup(key); ok {
+		return val, true
+	}
+	return c.resolveBelowFile(key)
+}
+
+// resolveAboveFile checks the flag, set and env layers
+func (c *configParserObj) resolveAboveFile(key string) (string, bool) {
+	lk := c.layerKey(key)
+	if binding, ok := c.flags[lk]; ok {
+		if val, ok := binding.value(); ok {
+			return val, true
+		}
+	}
+	if val, ok := c.overrides[lk]; ok {
+		return val, true
+	}
+	if c.env {
+		if val, ok := lookupEnv(c.envName(key)); ok {
+			return val, true
+		}
+	}
+	return "", false
+}
+
+// resolveBelowFile checks the computed and default layers
+func (c *configParserObj) resolveBelowFile(key string) (string, bool) {
+	lk := c.layerKey(key)
+	if fn, ok := c.computed[lk]; ok {
+		if val, ok := fn(); ok {
+			return val, true
+		}
+	}
+	if val, ok := c.defaults[lk]; ok {
+		return val, true
+	}
+	return "", false
+}
+
+// layerKeys lists keys that have a value in a layer other than file or env
+func (c *configParserObj) layerKeys() []string {
+	var keys []string
+	for key, binding := range c.flags {
+		if _, ok := binding.value(); ok {
+			keys = append(keys, key)
+		}
+	}
+	for key := range c.overrides {
+		keys = append(keys, key)
+	}
+	for key, fn := range c.computed {
+		if _, ok := fn(); ok {
+			keys = append(keys, key)
+		}
+	}
+	for key := range c.defaults {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// envName returns the environment variable read for a key
+func (c *configParserObj) envName(key string) string {
+	if c.scope != "" {
+		key = c.scope + "." + key
+	}
+	name := dotenvKey(c.layerKey(key))
+	if c.envPrefix != "" {
+		name = c.envPrefix + "_" + name
+	}
+	return name
+}
+
+// scopeLayers copies layer values below prefix into sub with the prefix removed
+func (c *configParserObj) scopeLayers(sub *configParserObj, key string) {
+	prefix := c.layerKey(key) + "."
+	sub.env = c.env
+	sub.envPrefix = c.envPrefix
+	sub.scope = c.layerKey(key)
+	if c.scope != "" {
+		sub.scope = c.scope + "." + sub.scope
+	}
+	sub.flags = make(map[string]flagBinding)
+	sub.overrides = make(map[string]string)
+	sub.computed = make(map[string]func() (string, bool))
+	sub.defaults = make(map[string]string)
+	for k, v := range c.flags {
+		if strings.HasPrefix(k, prefix) {
+			sub.flags[strings.TrimPrefix(k, prefix)] = v
+		}
+	}
+	for k, v := range c.overrides {
+		if strings.HasPrefix(k, prefix) {
+			sub.overrides[strings.TrimPrefix(k, prefix)] = v
+		}
+	}
+	for k, v := range c.computed {
+		if strings.HasPrefix(k, prefix) {
+			sub.computed[strings.TrimPrefix(k, prefix)] = v
+		}
+	}
+	for k, v := range c.defaults {
+		if strings.HasPrefix(k, prefix) {
+			sub.defaults[strings.TrimPrefix(k, prefix)] = v
+		}
+	}
+}
+
+// hasEnv reports whether any environment variable is set below a Sub's scope
+func (c *configParserObj) hasEnv() bool {
+	if !c.env || c.scope == "" {
+		return false
+	}
+	prefix := c.envName("")
+	for _, kv := range environ() {
+		if strings.HasPrefix(kv, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// layerKey normalizes a json/yaml key so equivalent paths, such as "list[0]"
+// and "list.0", share layer entries. Other formats use keys as written.
+func (c *configParserObj) layerKey(key string) string {
+	if !c.nested() {
+		return key
+	}
+	parts := splitKey(key)
+	for i, part := range parts {
+		parts[i] = strings.ReplaceAll(part, ".", `\.`)
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,345 @@
+package nafi
+
+import (
+	"flag"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Layer in the precedence matrix, ordered as in Precedence.
+//
+// Any new layer must be added here so every surface is checked against it.
+type precedenceLayer struct {
+	name  string
+	value int
+	// Configure the layer on a parser; the file layer is applied through content instead
+	apply func(t *testing.T, c *configParserObj, fs *flag.FlagSet, env map[string]string)
+}
+
+var precedenceLayers = []precedenceLayer{
+	{
+		name:  "flag",
+		value: 1,
+		apply: func(t *testing.T, _ *configParserObj, fs *flag.FlagSet, _ map[string]string) {
+			if err := fs.Parse([]string{"-port=1"}); err != nil {
+				t.Fatalf("flag parse error: %v", err)
+			}
+		},
+	},
+	{
+		name:  "set",
+		value: 2,
+		apply: func(_ *testing.T, c *configParserObj, _ *flag.FlagSet, _ map[string]string) {
+			c.Set("server.port", 2)
+		},
+	},
+	{
+		name:  "env",
+		value: 3,
+		apply: func(_ *testing.T, _ *configParserObj, _ *flag.FlagSet, env map[string]string) {
+			env["NAFITEST_SERVER_PORT"] = "3"
+		},
+	},
+	{
+		name:  "file",
+		value: 4,
+	},
+	{
+		name:  "computed",
+		value: 5,
+		apply: func(_ *testing.T, c *configParserObj, _ *flag.FlagSet, _ map[string]string) {
+			c.SetComputed("server.port", func() (string, bool) { return "5", true })
+		},
+	},
+	{
+		name:  "default",
+		value: 6,
+		apply: func(_ *testing.T, c *configParserObj, _ *flag.FlagSet, _ map[string]string) {
+			c.SetDefault("server.port", 6)
+		},
+	},
+}
+
+// build a parser with the given layers configuring server.port
+func newLayeredParser(t *testing.T, active map[string]bool) *configParserObj {
+	t.Helper()
+	env := make(map[string]string)
+	lookupEnv = func(key string) (string, bool) {
+		val, ok := env[key]
+		return val, ok
+	}
+	environ = func() []string {
+		var vars []string
+		for key, val := range env {
+			vars = append(vars, key+"="+val)
+		}
+		return vars
+	}
+	t.Cleanup(func() {
+		lookupEnv = os.LookupEnv
+		environ = os.Environ
+	})
+
+	content := "other: 0\n"
+	if active["file"] {
+		content = "server:\n  port: 4\n"
+	}
+	parser, err := newConfigParserFromBytes("yaml", []byte(content), WithEnvPrefix("NAFITEST"))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	// The flag is always bound, so an unset flag must not count
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("port", 0, "server port")
+	if err := parser.BindFlag("server.port", fs, "port"); err != nil {
+		t.Fatalf("BindFlag unexpected error: %v", err)
+	}
+	for _, layer := range precedenceLayers {
+		if active[layer.name] && layer.apply != nil {
+			layer.apply(t, parser, fs, env)
+		}
+	}
+	return parser
+}
+
+// Expected resolution of server.port
+type resolvedExpectation struct {
+	value int
+	found bool
+	// listed is false when only the environment sets the key, since
+	// environment variables cannot be enumerated
+	listed bool
+	kind   ValueKind
+}
+
+// check every read surface agrees on server.port
+func assertResolved(t *testing.T, parser *configParserObj, expected resolvedExpectation) {
+	t.Helper()
+	expectedStr := ""
+	if expected.found {
+		expectedStr = strconv.Itoa(expected.value)
+	}
+
+	if val, err := parser.Get("server.port"); err != nil || val != expectedStr {
+		t.Errorf("Get = %q, %v; want %q", val, err, expectedStr)
+	}
+	if has := parser.Has("server.port"); has != expected.found {
+		t.Errorf("Has = %v; want %v", has, expected.found)
+	}
+	if kind, ok := parser.Kind("server.port"); kind != expected.kind || ok != expected.found {
+		t.Errorf("Kind = %v, %v; want %v, %v", kind, ok, expected.kind, expected.found)
+	}
+	if n, err := parser.GetInt("server.port"); err != nil || n != expected.value {
+		t.Errorf("GetInt = %d, %v; want %d", n, err, expected.value)
+	}
+	if f, err := parser.GetFloat64("server.port"); err != nil || f != float64(expected.value) {
+		t.Errorf("GetFloat64 = %v, %v; want %d", f, err, expected.value)
+	}
+	var cfg struct {
+		Server struct {
+			Port int
+		}
+	}
+	if err := parser.Unmarshal(&cfg); err != nil || cfg.Server.Port != expected.value {
+		t.Errorf("Unmarshal Server.Port = %d, %v; want %d", cfg.Server.Port, err, expected.value)
+	}
+	if val, ok := parser.AsFlatMap()["server.port"]; ok != expected.listed || (ok && val != expectedStr) {
+		t.Errorf("AsFlatMap[server.port] = %q, %v; want %q, %v", val, ok, expectedStr, expected.listed)
+	}
+
+	sub := parser.Sub("server")
+	switch {
+	case !expected.found && sub != nil:
+		t.Errorf("Sub(server) = %v; want nil", sub)
+	case expected.found && sub == nil:
+		t.Errorf("Sub(server) = nil; want parser containing port")
+	case expected.found:
+		if val, _ := sub.Get("port"); val != expectedStr {
+			t.Errorf("Sub(server).Get(port) = %q; want %q", val, expectedStr)
+		}
+		if kind, ok := sub.Kind("port"); kind != expected.kind || !ok {
+			t.Errorf("Sub(server).Kind(port) = %v, %v; want %v, true", kind, ok, expected.kind)
+		}
+	}
+}
+
+// kind of server.port when resolved from a layer
+func layerKind(name string) ValueKind {
+	if name == "file" {
+		return KindScalar
+	}
+	return KindString
+}
+
+// Test every combination of each pair of adjacent layers resolves identically on all surfaces
+func TestPrecedenceMatrix(t *testing.T) {
+	// The matrix must cover exactly the documented layers
+	names := make([]string, 0, len(precedenceLayers))
+	for _, layer := range precedenceLayers {
+		names = append(names, layer.name)
+	}
+	if order := strings.Join(names, " > "); order != Precedence {
+		t.Fatalf("matrix layers %q do not match Precedence %q", order, Precedence)
+	}
+
+	for i := 0; i < len(precedenceLayers)-1; i++ {
+		high, low := precedenceLayers[i], precedenceLayers[i+1]
+		combos := []struct {
+			name      string
+			high, low bool
+			winner    *precedenceLayer
+		}{
+			{"neither", false, false, nil},
+			{"only " + low.name, false, true, &low},
+			{"only " + high.name, true, false, &high},
+			{"both", true, true, &high},
+		}
+		for _, combo := range combos {
+			t.Run(high.name+">"+low.name+"/"+combo.name, func(t *testing.T) {
+				active := map[string]bool{high.name: combo.high, low.name: combo.low}
+				var expected resolvedExpectation
+				if combo.winner != nil {
+					expected = resolvedExpectation{
+						value: combo.winner.value,
+						found: true,
+						// A value only in the environment is resolved but not listed
+						listed: !(active["env"] && !(combo.high && combo.low)),
+						kind:   layerKind(combo.winner.name),
+					}
+				}
+				assertResolved(t, newLayeredParser(t, active), expected)
+			})
+		}
+	}
+
+	t.Run("all layers", func(t *testing.T) {
+		active := make(map[string]bool)
+		for _, layer := range precedenceLayers {
+			active[layer.name] = true
+		}
+		assertResolved(t, newLayeredParser(t, active), resolvedExpectation{
+			value:  precedenceLayers[0].value,
+			found:  true,
+			listed: true,
+			kind:   layerKind(precedenceLayers[0].name),
+		})
+	})
+}
+
+// Test layer keys are normalized and listed
+func TestLayerKeys(t *testing.T) {
+	parser, _ := newConfigParserFromBytes("yaml", []byte("servers:\n  - host: a\n"))
+	parser.Set("servers[0].host", "b")
+	if val, _ := parser.Get("servers.0.host"); val != "b" {
+		t.Errorf("Get(%q) = %q; want %q", "servers.0.host", val, "b")
+	}
+
+	parser.SetDefault("db.name", "app")
+	parser.SetComputed("db.unset", func() (string, bool) { return "", false })
+	if keys := strings.Join(parser.Keys(), ","); keys != "db.name,servers.0.host" {
+		t.Errorf("Keys() = %s; want db.name,servers.0.host", keys)
+	}
+	if val := parser.AsFlatMap()["db.name"]; val != "app" {
+		t.Errorf("AsFlatMap()[db.name] = %q; want %q", val, "app")
+	}
+
+	// A layer entry for an escaped name replaces the file's key
+	dotted, _ := newConfigParserFromBytes("yaml", []byte("dotted.key: a\n"))
+	dotted.Set(`dotted\.key`, "b")
+	if flat := dotted.AsFlatMap(); len(flat) != 1 || flat[`dotted\.key`] != "b" {
+		t.Errorf("AsFlatMap() = %v; want single dotted\\.key entry of b", flat)
+	}
+}
+
+// Test Sub keeps layer values and environment names relative to the full key
+func TestLayersScopedBySub(t *testing.T) {
+	lookupEnv = func(key string) (string, bool) {
+		if key == "APP_SERVER_HOST" {
+			return "env-host", true
+		}
+		return "", false
+	}
+	defer func() { lookupEnv = os.LookupEnv }()
+
+	parser, _ := newConfigParserFromBytes("json", []byte(`{"server": {"host": "file-host"}}`), WithEnvPrefix("APP"))
+	parser.SetDefault("server.port", 8080)
+	sub := parser.Sub("server")
+	if sub == nil {
+		t.Fatalf("Sub(%q) = nil", "server")
+	}
+	if val, _ := sub.Get("host"); val != "env-host" {
+		t.Errorf("Sub(server).Get(host) = %q; want %q", val, "env-host")
+	}
+	if val, _ := sub.Get("port"); val != "8080" {
+		t.Errorf("Sub(server).Get(port) = %q; want %q", val, "8080")
+	}
+}
+
+// Test typed getters convert like Unmarshal and report bad values
+func TestTypedGetters(t *testing.T) {
+	parser, _ := newConfigParserFromBytes("ini", []byte("[app]\ndebug = true\ntimeout = 1m\nname = demo\n"))
+	if b, err := parser.GetBool("app.debug"); err != nil || !b {
+		t.Errorf("GetBool = %v, %v; want true", b, err)
+	}
+	if d, err := parser.GetDuration("app.timeout"); err != nil || d != time.Minute {
+		t.Errorf("GetDuration = %v, %v; want 1m", d, err)
+	}
+	if n, err := parser.GetInt("app.missing"); err != nil || n != 0 {
+		t.Errorf("GetInt(missing) = %d, %v; want 0, nil", n, err)
+	}
+	if _, err := parser.GetInt("app.name"); err == nil || !strings.Contains(err.Error(), `"app.name"`) {
+		t.Errorf("Expected conversion error naming app.name, got %v", err)
+	}
+
+	// JSON numbers decode as floats but must still convert to ints
+	jsonParser, _ := newConfigParserFromBytes("json", []byte(`{"limits": {"max_bytes": 10485760, "ratio": 2000000.5}}`))
+	if n, err := jsonParser.GetInt("limits.max_bytes"); err != nil || n != 10485760 {
+		t.Errorf("GetInt(json) = %d, %v; want 10485760", n, err)
+	}
+	if f, err := jsonParser.GetFloat64("limits.ratio"); err != nil || f != 2000000.5 {
+		t.Errorf("GetFloat64(json) = %v, %v; want 2000000.5", f, err)
+	}
+
+	unsupported := &configParserObj{fileType: "unsupported"}
+	if _, err := unsupported.GetInt("any"); err == nil {
+		t.Errorf("Expected error for unsupported file type")
+	}
+}
+
+// Test binding an undefined flag fails
+func TestBindFlagUndefined(t *testing.T) {
+	parser, _ := newConfigParserFromBytes("conf", []byte("port = 1"))
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	if err := parser.BindFlag("port", fs, "port"); err == nil {
+		t.Errorf("Expected error binding undefined flag")
+	}
+}
+
+// Test Kind and Sub see keys supplied only by other layers
+func TestLayerOnlyKindAndSub(t *testing.T) {
+	parser, _ := newConfigParserFromBytes("ini", []byte("[app]\nname = demo\n"))
+	parser.SetDefault("db.name", "x")
+	if kind, ok := parser.Kind("db.name"); kind != KindString || !ok {
+		t.Errorf("Kind(%q) = %v, %v; want %v, true", "db.name", kind, ok, KindString)
+	}
+	sub := parser.Sub("db")
+	if sub == nil {
+		t.Fatalf("Sub(%q) = nil; want parser with layer keys", "db")
+	}
+	if val, _ := sub.Get("name"); val != "x" {
+		t.Errorf("Sub(db).Get(name) = %q; want %q", val, "x")
+	}
+	if sub := parser.Sub("missing"); sub != nil {
+		t.Errorf("Sub(%q) = %v; want nil", "missing", sub)
+	}
+
+	// Layer keys for ini are kept as written
+	parser.Set("app.arr[0]", "y")
+	if keys := strings.Join(parser.Keys(), ","); keys != "app.arr[0],app.name,db.name" {
+		t.Errorf("Keys() = %s; want app.arr[0],app.name,db.name", keys)
+	}
+}
@@ -1,11 +1,17 @@
 package nafi
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"gopkg.in/ini.v1"
 	"gopkg.in/yaml.v3"
@@ -18,18 +24,91 @@ var readFile fileReaderFunc = os.ReadFile
 
 // Config parser object
 type configParserObj struct {
-	data     map[string]interface{}
-	raw      map[string]string
-	fileType string
-	iniFile  *ini.File
+	data            map[string]interface{}
+	raw             map[string]string
+	fileType        string
+	iniFile         *ini.File
+	fieldNameMapper func(string) string
+	logger          *log.Logger
+	embedded        bool
+	embeddedCache   *sync.Map
+	env             bool
+	envPrefix       string
+	scope           string
+	flags           map[string]flagBinding
+	overrides       map[string]string
+	computed        map[string]func() (string, bool)
+	defaults        map[string]string
+}
+
+// Option configures optional parser behaviour, passed to ConfigParser.
+type Option func(*configParserObj)
+
+// WithFieldNameMapper overrides the function Unmarshal uses to derive a key
+// from a struct field name when the field has no `nafi` tag.
+//
+// The default is DefaultFieldNameMapper.
+func WithFieldNameMapper(mapper func(string) string) Option {
+	return func(c *configParserObj) {
+		c.fieldNameMapper = mapper
+	}
+}
+
+// WithLogger sets the logger that receives non-fatal warnings, such as
+// inconsistent yaml indentation. Warnings are discarded when no logger is set.
+func WithLogger(logger *log.Logger) Option {
+	return func(c *configParserObj) {
+		c.logger = logger
+	}
+}
+
+// WithEmbeddedDocuments lets dot-path lookups continue into string values
+// that hold a JSON or YAML document, e.g. "policy.allow" where "policy" is the
+// string '{"allow": ["a", "b"]}'.
+//
+// Strings starting with "{" or "[" are parsed as JSON; other multi-line
+// strings are parsed as YAML. Anything that fails to parse is treated as a
+// plain string, so lookups below it are not found. Each string is parsed at
+// most once.
+func WithEmbeddedDocuments() Option {
+	return func(c *configParserObj) {
+		c.embedded = true
+	}
+}
+
+// WithEnvPrefix enables environment variable overrides. A key is read from
+// the variable named by the prefix and the key upper cased, with dots and other
+// invalid characters replaced by underscores, so with prefix "APP" the key
+// "server.port" reads APP_SERVER_PORT. An empty prefix reads SERVER_PORT.
+func WithEnvPrefix(prefix string) Option {
+	return func(c *configParserObj) {
+		c.env = true
+		c.envPrefix = prefix
+	}
+}
+
+// log a warning if a logger is configured
+func (c *configParserObj) warn(format string, args ...interface{}) {
+	if c.logger != nil {
+		c.logger.Printf("nafi: warning: "+format, args...)
+	}
 }
 
 // NewConfigParserFromBytes parses config data from a byte slice, based on the provided file type.
-func newConfigParserFromBytes(fileType string, content []byte) (*configParserObj, error) {
+func newConfigParserFromBytes(fileType string, content []byte, opts ...Option) (*configParserObj, error) {
 	parser := &configParserObj{
-		data:     make(map[string]interface{}),
-		raw:      make(map[string]string),
-		fileType: fileType,
+		data:            make(map[string]interface{}),
+		raw:             make(map[string]string),
+		fileType:        fileType,
+		fieldNameMapper: DefaultFieldNameMapper,
+		embeddedCache:   &sync.Map{},
+		flags:           make(map[string]flagBinding),
+		overrides:       make(map[string]string),
+		computed:        make(map[string]func() (string, bool)),
+		defaults:        make(map[string]string),
+	}
+	for _, opt := range opts {
+		opt(parser)
 	}
 
 	// Perform parsing based on filetype
@@ -48,6 +127,12 @@ func newConfigParserFromBytes(fileType string, content []byte) (*configParserObj
 				parser.raw[key] = val
 			}
 		}
+	case "dotenv":
+		values, err := parseDotenv(content)
+		if err != nil {
+			return nil, err
+		}
+		parser.raw = values
 	case "ini":
 		iniFile, err := ini.Load(content)
 		if err != nil {
@@ -55,99 +140,282 @@ func newConfigParserFromBytes(fileType string, content []byte) (*configParserObj
 		}
 		parser.iniFile = iniFile
 	case "json":
+		// Treat empty content as an empty object, as the other formats do
+		if len(bytes.TrimSpace(content)) == 0 {
+			break
+		}
 		var jsonData map[string]interface{}
 		if err := json.Unmarshal(content, &jsonData); err != nil {
 			return nil, err
 		}
 		parser.data = jsonData
 	case "yaml":
+		findings := lintYAML(content)
 		var yamlData map[string]interface{}
 		if err := yaml.Unmarshal(content, &yamlData); err != nil {
-			return nil, err
+			return nil, wrapYAMLError(err, findings)
+		}
+		for _, finding := range findings {
+			parser.warn("yaml: %s", finding)
 		}
 		parser.data = yamlData
 	default:
-		return nil, errors.New("unsupported file type " + fileType)
+		format, ok := lookupFormat(fileType)
+		if !ok {
+			return nil, errors.New("unsupported file type " + fileType)
+		}
+		data, err := format.Parse(content)
+		if err != nil {
+			return nil, err
+		}
+		parser.data = data
 	}
 	return parser, nil
 }
 
+// matches a list index written as "[n]"
+var indexPattern = regexp.MustCompile(`\[(\d+)\]`)
+
+// splitKey splits a dot notation key into path segments.
+//
+// "\." is a literal dot inside a segment and "[n]" is shorthand for ".n", so
+// "servers[0].host\.name" splits into "servers", "0" and "host.name".
+func splitKey(key string) []string {
+	key = indexPattern.ReplaceAllString(key, ".$1")
+	var parts []string
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		switch {
+		case key[i] == '\\' && i+1 < len(key) && key[i+1] == '.':
+			b.WriteByte('.')
+			i++
+		case key[i] == '.':
+			parts = append(parts, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(key[i])
+		}
+	}
+	return append(parts, b.String())
+}
+
+// retrieve a map entry or list element by path segment
+func childValue(current interface{}, part string) (interface{}, bool) {
+	switch curr := current.(type) {
+	case map[string]interface{}:
+		next, ok := curr[part]
+		return next, ok
+	case []interface{}:
+		idx, err := strconv.Atoi(part)
+		if err != nil || idx < 0 || idx >= len(curr) {
+			return nil, false
+		}
+		return curr[idx], true
+	default:
+		return nil, false
+	}
+}
+
 // retieve nested value from data
 func getNestedValue(data map[string]interface{}, key string) (interface{}, bool) {
-	parts := strings.Split(key, ".")
 	var current interface{} = data
-	for _, part := range parts {
-		switch curr := current.(type) {
-		case map[string]interface{}:
-			next, ok := curr[part]
-			if !ok {
-				return nil, false
-			}
-			current = next
-		default:
+	for _, part := range splitKey(key) {
+		next, ok := childValue(current, part)
+		if !ok {
+			return nil, false
+		}
+		current = next
+	}
+	return current, true
+}
+
+// retrieve nested value from parsed data, descending into embedded documents if enabled
+func (c *configParserObj) nestedValue(key string) (interface{}, bool) {
+	if !c.embedded {
+		return getNestedValue(c.data, key)
+	}
+	parts := splitKey(key)
+	var current interface{} = c.data
+	for i, part := range parts {
+		if s, ok := current.(string); ok {
+			current = c.embeddedDocument(strings.Join(parts[:i], "\x00"), s)
+		}
+		next, ok := childValue(current, part)
+		if !ok {
 			return nil, false
 		}
+		current = next
 	}
 	return current, true
 }
 
+// parse a string leaf as a document, caching the result by its path.
+// The cache is safe for concurrent lookups; if two lookups race, both parse
+// the same string and the first stored result wins.
+func (c *configParserObj) embeddedDocument(path string, s string) interface{} {
+	if doc, ok := c.embeddedCache.Load(path); ok {
+		return doc
+	}
+	var doc interface{}
+	trimmed := strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "["):
+		// YAML flow syntax such as "{allow: [a, b]}" is not valid JSON
+		if err := json.Unmarshal([]byte(trimmed), &doc); err != nil {
+			doc = nil
+			if err := yaml.Unmarshal([]byte(trimmed), &doc); err != nil {
+				doc = nil
+			}
+		}
+	case strings.Contains(trimmed, "\n"):
+		var yamlDoc map[string]interface{}
+		if err := yaml.Unmarshal([]byte(s), &yamlDoc); err == nil {
+			doc = yamlDoc
+		}
+	}
+	doc, _ = c.embeddedCache.LoadOrStore(path, doc)
+	return doc
+}
+
+// splitIniKey separates a key at its first dot into ini section and key name,
+// using the default section when the key has no dot. As in Keys, "\." is a
+// literal dot, so the default section's "a.b" is written "a\.b". Index syntax
+// only applies to nested data, so ini key names such as "arr[0]" are kept as is.
+func splitIniKey(key string) (string, string, bool) {
+	for i := 0; i < len(key); i++ {
+		switch {
+		case key[i] == '\\' && i+1 < len(key) && key[i+1] == '.':
+			i++
+		case key[i] == '.':
+			// An explicit empty section name is not the default section
+			if i == 0 {
+				return "", "", false
+			}
+			return unescapeDots(key[:i]), unescapeDots(key[i+1:]), true
+		}
+	}
+	return "", unescapeDots(key), true
+}
+
+// escapeDots writes the dots in a name as "\." so it reads as one path segment
+func escapeDots(name string) string {
+	return strings.ReplaceAll(name, ".", `\.`)
+}
+
+// unescapeDots reverses escapeDots
+func unescapeDots(name string) string {
+	return strings.ReplaceAll(name, `\.`, ".")
+}
+
 // Reads a filepath on the disk and parses it, returning a ConfigParserObj object.
 //
 // Supported file types:
 //
-// "conf", "ini", "json", "yaml"
-func ConfigParser(filepath string, fileType string) (configParserObj, error) {
+// "conf", "dotenv", "ini", "json", "yaml", and any format added with RegisterFormat
+func ConfigParser(filepath string, fileType string, opts ...Option) (configParserObj, error) {
 	content, err := readFile(filepath)
 	if err != nil {
 		return configParserObj{}, err
 	}
 
-	parser, err := newConfigParserFromBytes(fileType, content)
+	parser, err := newConfigParserFromBytes(fileType, content, opts...)
 	if err != nil {
 		return configParserObj{}, err
 	}
 	return *parser, nil
 }
 
-// Get returns the value for a key, using dot notation for sectioned/nested formats
+// ConfigParserFromBytes parses config content already in memory, returning a ConfigParserObj object.
+//
+// Supported file types are the same as for ConfigParser.
+func ConfigParserFromBytes(content []byte, fileType string, opts ...Option) (configParserObj, error) {
+	parser, err := newConfigParserFromBytes(fileType, content, opts...)
+	if err != nil {
+		return configParserObj{}, err
+	}
+	return *parser, nil
+}
+
+// Get returns the value for a key, using dot notation for sectioned/nested formats.
+// Values set by flags, Set, the environment, SetComputed or SetDefault are
+// resolved in the order described by Precedence.
 //
 // Example 1 - val, err := configParser.Get("foo")
 //
 // Example 2 - val, err := configParser.Get("foo.bar")
 func (c *configParserObj) Get(key string) (string, error) {
 	// Check filetype of parser
+	if !c.supported() {
+		return "", errors.New("unsupported file type " + c.fileType)
+	}
+	val, _ := c.resolve(key)
+	return val, nil
+}
+
+// Has reports whether a key is present, even if its value is empty
+func (c *configParserObj) Has(key string) bool {
+	_, found := c.resolve(key)
+	return found
+}
+
+// supported reports whether the parser's file type is known
+func (c *configParserObj) supported() bool {
 	switch c.fileType {
-	// Perform action for type conf
-	case "conf":
+	case "conf", "dotenv", "ini":
+		return true
+	}
+	return c.nested()
+}
+
+// nested reports whether values are held as nested maps in data
+func (c *configParserObj) nested() bool {
+	switch c.fileType {
+	case "json", "yaml":
+		return true
+	}
+	_, ok := lookupFormat(c.fileType)
+	return ok
+}
+
+// lookup returns the value for a key in the parsed file and whether the key is present
+func (c *configParserObj) lookup(key string) (string, bool) {
+	switch c.fileType {
+	// Perform action for type conf or dotenv
+	case "conf", "dotenv":
 		val, ok := c.raw[key]
-		if !ok {
-			return "", nil
-		}
-		return val, nil
+		return val, ok
 	// Perform action for type ini
 	case "ini":
-		if !strings.Contains(key, ".") {
-			val := c.iniFile.Section("").Key(key).String()
-			if val == "" {
-				return "", nil
-			}
-			return val, nil
+		section, k, ok := splitIniKey(key)
+		if !ok {
+			return "", false
 		}
-		parts := strings.SplitN(key, ".", 2)
-		section, k := parts[0], parts[1]
-		val := c.iniFile.Section(section).Key(k).String()
-		if val == "" {
-			return "", nil
+		sec, err := c.iniFile.GetSection(section)
+		if err != nil || !sec.HasKey(k) {
+			return "", false
 		}
-		return val, nil
-	// Perform action for type json or yaml
-	case "json", "yaml":
-		val, found := getNestedValue(c.data, key)
-		if !found {
-			return "", nil
-		}
-		return fmt.Sprintf("%v", val), nil
-	default:
-		return "", errors.New("unsupported file type " + c.fileType)
+		return sec.Key(k).String(), true
 	}
-}
\ No newline at end of file
+	// Perform action for type json, yaml or registered formats
+	if !c.nested() {
+		return "", false
+	}
+	val, found := c.nestedValue(key)
+	if !found {
+		return "", false
+	}
+	return formatValue(val), true
+}
+
+// formatValue stringifies a parsed value. Floats are written without an
+// exponent, so a JSON number such as 1000000 reads back as an integer, and
+// yaml timestamps use RFC 3339.
+func formatValue(val interface{}) string {
+	switch v := val.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprintf("%v", val)
+}
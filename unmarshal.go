@@ -0,0 +1,241 @@
+package nafi
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// DefaultFieldNameMapper converts a Go field name to the snake_case key used
+// when a struct field has no `nafi` tag.
+//
+// Runs of capitals are treated as a single initialism, so "BaseURL" maps to
+// "base_url", "HTTPServer" to "http_server" and "UserIDs" to "user_ids".
+// Section names follow the same rule, so a field "Server" reads the "server"
+// section of an ini file or the "server" map of a yaml/json file.
+func DefaultFieldNameMapper(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if !unicode.IsUpper(r) {
+			b.WriteRune(r)
+			continue
+		}
+		if i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			// Keep a pluralised initialism ("IDs") together
+			plural := nextLower && runes[i+1] == 's' && (i+2 == len(runes) || !unicode.IsLower(runes[i+2]))
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower && !plural) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// Unmarshal decodes the parsed config into the struct pointed to by v.
+//
+// Each exported field reads the key named by its `nafi` tag, or the name
+// produced by the field name mapper when untagged. Nested structs descend one
+// level using dot notation, so they map to ini sections or nested yaml/json
+// maps. A tag of "-" skips the field and missing keys leave fields untouched.
+// Values are resolved exactly as Get resolves them, following Precedence.
+//
+// Supported field types are strings, bools, integers, floats, time.Duration,
+// types implementing encoding.TextUnmarshaler such as time.Time, and slices of
+// these. A slice reads a yaml/json list element by element; any other value,
+// such as an ini value or an environment variable, is split on commas. Other
+// field types return an error.
+//
+// Example - err := configParser.Unmarshal(&cfg)
+func (c *configParserObj) Unmarshal(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("unmarshal target must be a non-nil pointer to a struct")
+	}
+	return c.unmarshalStruct(rv.Elem(), "")
+}
+
+// populate struct fields from keys below prefix
+func (c *configParserObj) unmarshalStruct(rv reflect.Value, prefix string) error {
+	mapper := c.fieldNameMapper
+	if mapper == nil {
+		mapper = DefaultFieldNameMapper
+	}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, tagged := field.Tag.Lookup("nafi")
+		if name == "-" {
+			continue
+		}
+		fv := rv.Field(i)
+
+		// Types that decode themselves from text are leaves, even when they are structs
+		leaf := reflect.PointerTo(field.Type).Implements(textUnmarshalerType)
+
+		// Untagged embedded structs share the parent's keys
+		if field.Anonymous && !tagged && field.Type.Kind() == reflect.Struct && !leaf {
+			if err := c.unmarshalStruct(fv, prefix); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if name == "" {
+			name = mapper(field.Name)
+		}
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		if field.Type.Kind() == reflect.Struct && !leaf {
+			if err := c.unmarshalStruct(fv, key); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if field.Type.Kind() == reflect.Slice && !leaf {
+			vals, found, err := c.resolveList(key)
+			if err == nil && found {
+				err = setSliceFromStrings(fv, vals)
+			}
+			if err != nil {
+				return fmt.Errorf("cannot unmarshal key %q into field %s: %w", key, field.Name, err)
+			}
+			continue
+		}
+
+		val, found := c.resolve(key)
+		if !found {
+			continue
+		}
+		if err := setFieldFromString(fv, val); err != nil {
+			return fmt.Errorf("cannot unmarshal key %q into field %s: %w", key, field.Name, err)
+		}
+	}
+	return nil
+}
+
+// resolveList returns the effective value for a key as a list, following
+// Precedence like resolve. A yaml/json list keeps its elements and any other
+// value is split on commas.
+func (c *configParserObj) resolveList(key string) ([]string, bool, error) {
+	if val, ok := c.resolveAboveFile(key); ok {
+		return splitList(val), true, nil
+	}
+	if c.nested() {
+		if val, ok := c.nestedValue(key); ok {
+			switch v := val.(type) {
+			case nil:
+				return nil, true, nil
+			case []interface{}:
+				list := make([]string, len(v))
+				for i, item := range v {
+					switch item.(type) {
+					case map[string]interface{}, []interface{}:
+						return nil, false, fmt.Errorf("list element %d is not a scalar", i)
+					}
+					list[i] = formatValue(item)
+				}
+				return list, true, nil
+			}
+		}
+	}
+	if val, ok := c.lookup(key); ok {
+		return splitList(val), true, nil
+	}
+	if val, ok := c.resolveBelowFile(key); ok {
+		return splitList(val), true, nil
+	}
+	return nil, false, nil
+}
+
+// split a comma separated value, trimming spaces around each element
+func splitList(val string) []string {
+	if strings.TrimSpace(val) == "" {
+		return nil
+	}
+	list := strings.Split(val, ",")
+	for i := range list {
+		list[i] = strings.TrimSpace(list[i])
+	}
+	return list
+}
+
+// convert each string to the slice's element type and assign the slice
+func setSliceFromStrings(fv reflect.Value, vals []string) error {
+	slice := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+	for i, val := range vals {
+		if err := setFieldFromString(slice.Index(i), val); err != nil {
+			return fmt.Errorf("element %d: %w", i, err)
+		}
+	}
+	fv.Set(slice)
+	return nil
+}
+
+// convert a string value to the field's type and assign it
+func setFieldFromString(fv reflect.Value, val string) error {
+	if fv.CanAddr() {
+		if u, ok := fv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(val))
+		}
+	}
+	if fv.Type() == durationType {
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	default:
+		return errors.New("unsupported field type " + fv.Type().String())
+	}
+	return nil
+}
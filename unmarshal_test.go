@@ -0,0 +1,240 @@
+package nafi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Untagged struct shared by the ini and yaml decoding tests
+type untaggedConfig struct {
+	AppName   string
+	Debug     bool
+	StartedAt time.Time
+	Server    struct {
+		Host    string
+		Port    int
+		BaseURL string
+		Timeout time.Duration
+		Aliases []string
+		Ports   []int
+	}
+	Database struct {
+		UserID   int64
+		MaxBytes int
+		Ratio    float64
+		Password string `nafi:"secret"`
+		Ignored  string `nafi:"-"`
+	}
+}
+
+// Test field names map to snake_case keys
+func TestDefaultFieldNameMapper(t *testing.T) {
+	cases := map[string]string{
+		"Host":       "host",
+		"AppName":    "app_name",
+		"URL":        "url",
+		"ID":         "id",
+		"BaseURL":    "base_url",
+		"UserID":     "user_id",
+		"UserIDs":    "user_ids",
+		"HTTPServer": "http_server",
+		"IDsList":    "ids_list",
+		"V2Config":   "v2_config",
+		"already_ok": "already_ok",
+	}
+	for in, expected := range cases {
+		if got := DefaultFieldNameMapper(in); got != expected {
+			t.Errorf("DefaultFieldNameMapper(%q) = %q; want %q", in, got, expected)
+		}
+	}
+}
+
+// Test an untagged struct decodes identically from ini and yaml
+func TestUnmarshalUntagged(t *testing.T) {
+	iniContent := `
+app_name = demo
+debug = true
+started_at = 2024-01-02T03:04:05Z
+
+[server]
+host = localhost
+port = 8080
+base_url = https://example.com
+timeout = 5s
+aliases = web, www
+ports = 80,443
+
+[database]
+user_id = 42
+max_bytes = 10485760
+ratio = 0.5
+secret = hunter2
+ignored = nope
+`
+	yamlContent := `
+app_name: demo
+debug: true
+started_at: 2024-01-02T03:04:05Z
+server:
+  host: localhost
+  port: 8080
+  base_url: https://example.com
+  timeout: 5s
+  aliases: [web, www]
+  ports: [80, 443]
+database:
+  user_id: 42
+  max_bytes: 10485760
+  ratio: 0.5
+  secret: hunter2
+  ignored: nope
+`
+	jsonContent := `{
+  "app_name": "demo",
+  "debug": true,
+  "started_at": "2024-01-02T03:04:05Z",
+  "server": {
+    "host": "localhost",
+    "port": 8080,
+    "base_url": "https://example.com",
+    "timeout": "5s",
+    "aliases": ["web", "www"],
+    "ports": [80, 443]
+  },
+  "database": {
+    "user_id": 42,
+    "max_bytes": 10485760,
+    "ratio": 0.5,
+    "secret": "hunter2",
+    "ignored": "nope"
+  }
+}`
+	decode := func(fileType, content string) untaggedConfig {
+		parser, err := newConfigParserFromBytes(fileType, []byte(content))
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		var cfg untaggedConfig
+		if err := parser.Unmarshal(&cfg); err != nil {
+			t.Fatalf("Unmarshal(%s) unexpected error: %v", fileType, err)
+		}
+		return cfg
+	}
+
+	fromIni := decode("ini", iniContent)
+	fromYaml := decode("yaml", yamlContent)
+	fromJSON := decode("json", jsonContent)
+	if !reflect.DeepEqual(fromIni, fromYaml) {
+		t.Errorf("ini and yaml decoded differently:\nini:  %+v\nyaml: %+v", fromIni, fromYaml)
+	}
+	if !reflect.DeepEqual(fromJSON, fromYaml) {
+		t.Errorf("json and yaml decoded differently:\njson: %+v\nyaml: %+v", fromJSON, fromYaml)
+	}
+
+	var expected untaggedConfig
+	expected.AppName = "demo"
+	expected.Debug = true
+	expected.StartedAt = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	expected.Server.Host = "localhost"
+	expected.Server.Port = 8080
+	expected.Server.BaseURL = "https://example.com"
+	expected.Server.Timeout = 5 * time.Second
+	expected.Server.Aliases = []string{"web", "www"}
+	expected.Server.Ports = []int{80, 443}
+	expected.Database.UserID = 42
+	expected.Database.MaxBytes = 10485760
+	expected.Database.Ratio = 0.5
+	expected.Database.Password = "hunter2"
+	if !reflect.DeepEqual(fromYaml, expected) {
+		t.Errorf("Unmarshal = %+v; want %+v", fromYaml, expected)
+	}
+}
+
+// Test a custom field name mapper replaces the default
+func TestUnmarshalWithFieldNameMapper(t *testing.T) {
+	parser, err := newConfigParserFromBytes("json", []byte(`{"HOST": "a", "port": "1"}`),
+		WithFieldNameMapper(strings.ToUpper))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	var cfg struct {
+		Host string
+		Port string `nafi:"port"`
+	}
+	if err := parser.Unmarshal(&cfg); err != nil {
+		t.Fatalf("Unmarshal unexpected error: %v", err)
+	}
+	if cfg.Host != "a" || cfg.Port != "1" {
+		t.Errorf("Unmarshal = %+v; want Host a, Port 1", cfg)
+	}
+}
+
+// Test Unmarshal error paths
+func TestUnmarshalErrors(t *testing.T) {
+	parser, _ := newConfigParserFromBytes("conf", []byte("port = eighty"))
+
+	t.Run("non pointer target", func(t *testing.T) {
+		var cfg struct{ Port int }
+		if err := parser.Unmarshal(cfg); err == nil {
+			t.Errorf("Expected error for non pointer target")
+		}
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		var cfg struct{ Port int }
+		err := parser.Unmarshal(&cfg)
+		if err == nil || !strings.Contains(err.Error(), `"port"`) {
+			t.Errorf("Expected error naming key port, got %v", err)
+		}
+	})
+
+	t.Run("unsupported field type", func(t *testing.T) {
+		var cfg struct{ Port map[string]string }
+		if err := parser.Unmarshal(&cfg); err == nil {
+			t.Errorf("Expected unsupported field type error")
+		}
+	})
+
+	t.Run("invalid list element", func(t *testing.T) {
+		var cfg struct{ Ports []int }
+		listParser, _ := newConfigParserFromBytes("yaml", []byte("ports: [80, http]"))
+		err := listParser.Unmarshal(&cfg)
+		if err == nil || !strings.Contains(err.Error(), "element 1") {
+			t.Errorf("Expected error naming element 1, got %v", err)
+		}
+	})
+
+	t.Run("list of maps", func(t *testing.T) {
+		var cfg struct{ Ports []string }
+		listParser, _ := newConfigParserFromBytes("yaml", []byte("ports:\n  - a: 1\n"))
+		if err := listParser.Unmarshal(&cfg); err == nil {
+			t.Errorf("Expected error for list of maps")
+		}
+	})
+
+	t.Run("invalid time", func(t *testing.T) {
+		var cfg struct{ Port time.Time }
+		if err := parser.Unmarshal(&cfg); err == nil || !strings.Contains(err.Error(), `"port"`) {
+			t.Errorf("Expected error naming key port, got %v", err)
+		}
+	})
+}
+
+// Test list fields split values from other layers on commas
+func TestUnmarshalListLayers(t *testing.T) {
+	parser, _ := newConfigParserFromBytes("yaml", []byte("hosts: [a]\n"))
+	parser.Set("hosts", "b, c")
+	parser.SetDefault("ports", "80,443")
+	var cfg struct {
+		Hosts []string
+		Ports []int
+	}
+	if err := parser.Unmarshal(&cfg); err != nil {
+		t.Fatalf("Unmarshal unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.Hosts, []string{"b", "c"}) || !reflect.DeepEqual(cfg.Ports, []int{80, 443}) {
+		t.Errorf("Unmarshal = %+v; want Hosts [b c], Ports [80 443]", cfg)
+	}
+}
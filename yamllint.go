@@ -0,0 +1,138 @@
+package nafi
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// matches a value that opens a block scalar, e.g. "key: |" or "- >-"
+var blockScalarPattern = regexp.MustCompile(`(^|[:-]\s+)[|>][-+0-9]*\s*(#.*)?$`)
+
+// Indentation problem found before yaml decoding
+type yamlLintFinding struct {
+	line int
+	tab  bool
+	msg  string
+}
+
+func (f yamlLintFinding) String() string {
+	return f.msg
+}
+
+// lintYAML scans yaml content for tab indentation and inconsistent
+// indentation widths, the usual causes of the decoder's least helpful errors.
+func lintYAML(content []byte) []yamlLintFinding {
+	var findings []yamlLintFinding
+	var levels []int  // indentation of enclosing lines
+	unit := 0         // indentation step established by the first nested line
+	blockParent := -1 // indentation of the line opening a block scalar
+	blockIndent := -1 // indentation of a block scalar's first content line
+
+	for i, line := range strings.Split(string(content), "\n") {
+		lineNo := i + 1
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimLeft(line, " \t")
+		leading := line[:len(line)-len(trimmed)]
+		indent := len(leading)
+
+		// Block scalar contents are free text once indented by spaces. Only
+		// spaces count, so a tab that starts the first line ends the scalar
+		// and is reported below like any other tab indentation.
+		if blockParent >= 0 {
+			spaces := len(line) - len(strings.TrimLeft(line, " "))
+			if trimmed == "" || (blockIndent < 0 && spaces > blockParent) {
+				if trimmed != "" {
+					blockIndent = spaces
+				}
+				continue
+			}
+			if blockIndent >= 0 && spaces >= blockIndent {
+				continue
+			}
+			blockParent, blockIndent = -1, -1
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "---") || strings.HasPrefix(trimmed, "...") {
+			continue
+		}
+
+		if strings.Contains(leading, "\t") {
+			msg := fmt.Sprintf("line %d begins with a tab; YAML requires spaces", lineNo)
+			if !strings.HasPrefix(leading, "\t") {
+				msg = fmt.Sprintf("line %d has a tab in its indentation; YAML requires spaces", lineNo)
+			}
+			findings = append(findings, yamlLintFinding{line: lineNo, tab: true, msg: msg})
+			continue
+		}
+
+		// Pop enclosing levels until this line fits
+		dedented := false
+		for len(levels) > 0 && levels[len(levels)-1] > indent {
+			levels = levels[:len(levels)-1]
+			dedented = true
+		}
+		parent := 0
+		if len(levels) > 0 {
+			parent = levels[len(levels)-1]
+		}
+		switch {
+		case dedented && indent != parent:
+			// Dedented to a column between two enclosing levels
+			findings = append(findings, yamlLintFinding{
+				line: lineNo,
+				msg:  fmt.Sprintf("line %d is indented by %d spaces, which matches no enclosing level", lineNo, indent),
+			})
+		case indent > parent:
+			delta := indent - parent
+			if unit == 0 {
+				unit = delta
+			} else if delta != unit {
+				findings = append(findings, yamlLintFinding{
+					line: lineNo,
+					msg:  fmt.Sprintf("line %d is indented by %d spaces; earlier lines use %d", lineNo, delta, unit),
+				})
+			}
+		}
+
+		// Content after a list dash is indented relative to the dash
+		effective := indent
+		for strings.HasPrefix(trimmed, "- ") {
+			trimmed = strings.TrimLeft(trimmed[1:], " ")
+			effective = len(line) - len(trimmed)
+		}
+		if len(levels) == 0 || levels[len(levels)-1] != indent {
+			levels = append(levels, indent)
+		}
+		if effective != indent {
+			levels = append(levels, effective)
+		}
+
+		if blockScalarPattern.MatchString(trimmed) {
+			blockParent = indent
+		}
+	}
+	return findings
+}
+
+// yamlLintHint picks the finding most likely to explain a decode error,
+// preferring tabs over width mismatches.
+func yamlLintHint(findings []yamlLintFinding) (yamlLintFinding, bool) {
+	for _, f := range findings {
+		if f.tab {
+			return f, true
+		}
+	}
+	if len(findings) > 0 {
+		return findings[0], true
+	}
+	return yamlLintFinding{}, false
+}
+
+// wrap a yaml decode error with the most relevant lint hint
+func wrapYAMLError(err error, findings []yamlLintFinding) error {
+	hint, ok := yamlLintHint(findings)
+	if !ok {
+		return err
+	}
+	return fmt.Errorf("%w (hint: %s)", err, hint)
+}
@@ -0,0 +1,123 @@
+package nafi
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+// Test tab-indented yaml reports the offending line instead of only the decoder error
+func TestYAMLTabIndentationHint(t *testing.T) {
+	content := "server:\n  host: localhost\n\tport: 8080\n"
+	_, err := newConfigParserFromBytes("yaml", []byte(content))
+	if err == nil {
+		t.Fatalf("Expected yaml parse error, got nil")
+	}
+	if !strings.Contains(err.Error(), "line 3 begins with a tab; YAML requires spaces") {
+		t.Errorf("Expected tab hint for line 3, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "yaml:") {
+		t.Errorf("Expected original decoder error to be kept, got %v", err)
+	}
+}
+
+// Test a tab starting a block scalar's content gets the same hint
+func TestYAMLBlockScalarTabHint(t *testing.T) {
+	_, err := newConfigParserFromBytes("yaml", []byte("script: |\n\techo a\n"))
+	if err == nil {
+		t.Fatalf("Expected yaml parse error, got nil")
+	}
+	if !strings.Contains(err.Error(), "line 2 begins with a tab; YAML requires spaces") {
+		t.Errorf("Expected tab hint for line 2, got %v", err)
+	}
+}
+
+// Test lint findings for a range of indentation styles
+func TestLintYAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		messages []string
+	}{
+		{
+			name:    "consistent two space",
+			content: "a:\n  b:\n    c: 1\n  d: 2\ne: 3\n",
+		},
+		{
+			name:    "four space lists",
+			content: "items:\n    - name: a\n      val: b\n    - name: c\nnext: 1\n",
+		},
+		{
+			name:    "unindented list",
+			content: "items:\n- a\n- b\n",
+		},
+		{
+			name:    "block scalar with tabs",
+			content: "script: |\n  echo a\n  \techo b\n\nafter: 1\n",
+		},
+		{
+			name:    "comments and documents",
+			content: "---\n# comment\n\t# tabbed comment\na: 1\n",
+		},
+		{
+			name:     "tab starting block scalar",
+			content:  "script: |\n\techo a\n",
+			messages: []string{"line 2 begins with a tab; YAML requires spaces"},
+		},
+		{
+			name:     "tab inside block scalar",
+			content:  "script: |\n  echo a\n\techo b\n",
+			messages: []string{"line 3 begins with a tab; YAML requires spaces"},
+		},
+		{
+			name:     "tab after spaces",
+			content:  "a:\n  \tb: 1\n",
+			messages: []string{"line 2 has a tab in its indentation; YAML requires spaces"},
+		},
+		{
+			name:     "mixed widths",
+			content:  "a:\n  b:\n      c: 1\n",
+			messages: []string{"line 3 is indented by 4 spaces; earlier lines use 2"},
+		},
+		{
+			name:     "unmatched dedent",
+			content:  "a:\n    b: 1\n  c: 2\n",
+			messages: []string{"line 3 is indented by 2 spaces, which matches no enclosing level"},
+		},
+		{
+			name:     "dedent below nesting",
+			content:  "a:\n  b:\n      c: 1\n    d: 2\n",
+			messages: []string{"line 3 is indented by 4 spaces; earlier lines use 2", "line 4 is indented by 4 spaces, which matches no enclosing level"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := lintYAML([]byte(tt.content))
+			var messages []string
+			for _, f := range findings {
+				messages = append(messages, f.msg)
+			}
+			if strings.Join(messages, "\n") != strings.Join(tt.messages, "\n") {
+				t.Errorf("lintYAML findings = %q; want %q", messages, tt.messages)
+			}
+		})
+	}
+}
+
+// Test findings on valid yaml are logged as warnings
+func TestYAMLLintWarningsLogged(t *testing.T) {
+	var buf bytes.Buffer
+	content := "a:\n  b:\n      c: 1\n"
+	parser, err := newConfigParserFromBytes("yaml", []byte(content), WithLogger(log.New(&buf, "", 0)))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if val, _ := parser.Get("a.b.c"); val != "1" {
+		t.Errorf("Get(%q) = %q; want %q", "a.b.c", val, "1")
+	}
+	if !strings.Contains(buf.String(), "line 3 is indented by 4 spaces; earlier lines use 2") {
+		t.Errorf("Expected indentation warning in log, got %q", buf.String())
+	}
+}